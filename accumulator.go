@@ -33,6 +33,16 @@ const (
 	seedFileUpdateInterval = 10 * time.Minute
 )
 
+// generatorBackend is the interface which pluggable Fortuna
+// generator implementations must satisfy in order to be used as the
+// randomness source inside an Accumulator.  Generator and
+// ChaCha20Generator both implement this interface.
+type generatorBackend interface {
+	Reseed(seed []byte)
+	PseudoRandomData(n uint) []byte
+	reset()
+}
+
 // Accumulator holds the state of one instance of the Fortuna random
 // number generator.  Randomness can be extracted using the
 // RandomData() and Read() methods.  Entropy from the environment
@@ -46,7 +56,7 @@ type Accumulator struct {
 	stopAutoSave chan<- bool
 
 	genMutex sync.Mutex
-	gen      *Generator
+	gen      generatorBackend
 
 	poolMutex    sync.Mutex
 	reseedCount  int
@@ -58,6 +68,13 @@ type Accumulator struct {
 	nextSource  uint8
 	stopSources chan bool
 	sources     sync.WaitGroup
+
+	healthMutex sync.Mutex
+	health      map[uint8]*sourceHealth
+
+	seedMutex sync.Mutex
+	seedCond  *sync.Cond
+	seedBits  float64
 }
 
 // NewRNG allocates a new instance of the Fortuna random number
@@ -95,13 +112,32 @@ var (
 // NewRNG(seedFileName).  See the documentation for NewRNG() for more
 // information.
 func NewAccumulator(newCipher NewCipher, seedFileName string) (*Accumulator, error) {
+	return newAccumulator(NewGenerator(newCipher), seedFileName)
+}
+
+// NewChaCha20Accumulator allocates a new instance of the Fortuna
+// random number generator which uses ChaCha20 instead of a block
+// cipher to produce its output.  See the documentation for
+// ChaCha20Generator for details of the underlying construction, and
+// the documentation for NewRNG() for a description of the
+// seedFileName argument.
+func NewChaCha20Accumulator(seedFileName string) (*Accumulator, error) {
+	return newAccumulator(NewChaCha20Generator(), seedFileName)
+}
+
+// newAccumulator does the actual work of allocating an Accumulator
+// around an already-constructed generator backend.  NewAccumulator
+// and NewChaCha20Accumulator are thin wrappers around this function.
+func newAccumulator(gen generatorBackend, seedFileName string) (*Accumulator, error) {
 	acc := &Accumulator{
-		gen: NewGenerator(newCipher),
+		gen: gen,
 	}
 	for i := 0; i < len(acc.pool); i++ {
 		acc.pool[i] = sha256d.New()
 	}
 	acc.stopSources = make(chan bool)
+	acc.health = make(map[uint8]*sourceHealth)
+	acc.seedCond = sync.NewCond(&acc.seedMutex)
 
 	if seedFileName != "" {
 		seedFile, err := os.OpenFile(seedFileName,
@@ -0,0 +1,134 @@
+// chacha20_generator.go - a ChaCha20-based alternative to Generator
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/seehuhn/sha256d"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	chaCha20KeySize = 32
+
+	// chaCha20BlockSize is the size of one ChaCha20 keystream block.
+	// The golang.org/x/crypto/chacha20 package deliberately does not
+	// export this as a constant, since it treats ChaCha20 as a plain
+	// stream cipher with no block-alignment requirement; we still
+	// need it here to size the blocks nextBlockLocked generates.
+	chaCha20BlockSize = 64
+
+	chaCha20MaxBlocks = 1 << 20
+)
+
+// ChaCha20Generator is a pseudo-random number generator which
+// follows the same Fortuna generator invariants as Generator, but
+// produces its output by generating ChaCha20 keystream blocks
+// directly instead of encrypting a counter with a block cipher.
+// This avoids the counter-mode plumbing PseudoRandomData needs for
+// block ciphers and gives a fast, software-friendly alternative on
+// platforms without AES-NI.
+//
+// A newly allocated ChaCha20Generator holds an all-zero key; until
+// Reseed has been called, its output is the fixed ChaCha20 keystream
+// of that all-zero key.
+//
+// It is safe to access a ChaCha20Generator concurrently from
+// different goroutines.
+type ChaCha20Generator struct {
+	mutex   sync.Mutex
+	key     [chaCha20KeySize]byte
+	counter uint64
+}
+
+// NewChaCha20Generator allocates a new ChaCha20-based Fortuna
+// generator.
+func NewChaCha20Generator() *ChaCha20Generator {
+	return &ChaCha20Generator{}
+}
+
+// Reseed updates the generator's key by hashing the old key
+// together with seed, using the same invariant as Generator.Reseed:
+// key = SHA256d(key || seed).  The block counter is reset to zero.
+func (g *ChaCha20Generator) Reseed(seed []byte) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	h := sha256d.New()
+	h.Write(g.key[:])
+	h.Write(seed)
+	copy(g.key[:], h.Sum(nil))
+	g.counter = 0
+}
+
+// nextBlockLocked returns the next 64-byte ChaCha20 keystream block
+// and advances the counter.  g.mutex must be held by the caller.
+func (g *ChaCha20Generator) nextBlockLocked() []byte {
+	var nonce [chacha20.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[chacha20.NonceSize-8:], g.counter)
+	g.counter++
+
+	c, err := chacha20.NewUnauthenticatedCipher(g.key[:], nonce[:])
+	if err != nil {
+		// Cannot happen: g.key and nonce always have the sizes
+		// required by chacha20.NewUnauthenticatedCipher.
+		panic(err)
+	}
+	block := make([]byte, chaCha20BlockSize)
+	c.XORKeyStream(block, block)
+	return block
+}
+
+// PseudoRandomData returns n pseudo-random bytes.  At most
+// chaCha20MaxBlocks*chaCha20BlockSize bytes can be requested in a
+// single call, to bound the amount of generator state which a
+// single request can expose.  After the requested data has been
+// produced, two further keystream blocks are generated and their
+// first chaCha20KeySize bytes become the generator's new key, so that
+// the data just returned cannot be
+// reconstructed from the generator's state afterwards.
+func (g *ChaCha20Generator) PseudoRandomData(n uint) []byte {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	numBlocks := (n + chaCha20BlockSize - 1) / chaCha20BlockSize
+	if numBlocks > chaCha20MaxBlocks {
+		panic("fortuna: too many pseudo-random bytes requested at once")
+	}
+
+	res := make([]byte, 0, numBlocks*chaCha20BlockSize)
+	for i := uint(0); i < numBlocks; i++ {
+		res = append(res, g.nextBlockLocked()...)
+	}
+
+	newKey := append(g.nextBlockLocked(), g.nextBlockLocked()...)
+	copy(g.key[:], newKey[:chaCha20KeySize])
+
+	return res[:n]
+}
+
+// reset erases the generator's key so that previously generated
+// output cannot be recovered from it and the generator can no
+// longer be used.
+func (g *ChaCha20Generator) reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	wipe(g.key[:])
+	g.counter = 0
+}
@@ -0,0 +1,82 @@
+// chacha20_generator_test.go - unit tests for chacha20_generator.go
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChaCha20GeneratorDeterministic(t *testing.T) {
+	g1 := NewChaCha20Generator()
+	g1.Reseed([]byte("test seed"))
+	out1 := g1.PseudoRandomData(100)
+
+	g2 := NewChaCha20Generator()
+	g2.Reseed([]byte("test seed"))
+	out2 := g2.PseudoRandomData(100)
+
+	if !bytes.Equal(out1, out2) {
+		t.Error("the same seed produced different output")
+	}
+}
+
+func TestChaCha20GeneratorKeyRotation(t *testing.T) {
+	g := NewChaCha20Generator()
+	g.Reseed([]byte("another seed"))
+	first := g.PseudoRandomData(64)
+	second := g.PseudoRandomData(64)
+
+	if bytes.Equal(first, second) {
+		t.Error("consecutive requests returned identical data")
+	}
+}
+
+func TestChaCha20GeneratorReseedChangesOutput(t *testing.T) {
+	g := NewChaCha20Generator()
+	g.Reseed([]byte("seed one"))
+	out1 := g.PseudoRandomData(32)
+
+	g.Reseed([]byte("seed two"))
+	out2 := g.PseudoRandomData(32)
+
+	if bytes.Equal(out1, out2) {
+		t.Error("different seeds produced identical output")
+	}
+}
+
+func TestChaCha20GeneratorTooManyBytesPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an oversized request")
+		}
+	}()
+
+	g := NewChaCha20Generator()
+	g.Reseed([]byte("seed"))
+	g.PseudoRandomData((chaCha20MaxBlocks + 1) * chaCha20BlockSize)
+}
+
+func TestChaCha20GeneratorReset(t *testing.T) {
+	g := NewChaCha20Generator()
+	g.Reseed([]byte("seed"))
+	g.reset()
+
+	if !isZero(g.key[:]) {
+		t.Error("reset did not wipe the key")
+	}
+}
@@ -0,0 +1,89 @@
+// entropy.go - submitting entropy into an Accumulator
+// Copyright (C) 2013  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import "time"
+
+// NewEntropyDataSink allocates a new entropy source and returns a
+// channel which the caller can use to submit entropy samples.  Every
+// slice sent on the returned channel is recorded by the
+// health-monitoring subsystem (see SourceStats and WaitForSeed) and
+// then mixed into one of the accumulator's pools, cycling through the
+// pools in turn as required by the Fortuna algorithm.
+//
+// The caller should close the channel once no more samples will be
+// submitted; the background goroutine started by NewEntropyDataSink
+// exits in response.
+func (acc *Accumulator) NewEntropyDataSink() chan<- []byte {
+	source := acc.allocateSourceID()
+	ch := make(chan []byte, 1)
+	go acc.mergeEntropyData(source, ch)
+	return ch
+}
+
+// NewEntropyTimeStampSink is like NewEntropyDataSink, but for sources
+// which only want to contribute the unpredictability of their event
+// timing, not of any associated data: each value sent on the returned
+// channel causes the current time to be mixed into the accumulator's
+// pools as if it were the submitted sample.
+func (acc *Accumulator) NewEntropyTimeStampSink() chan<- struct{} {
+	source := acc.allocateSourceID()
+	ch := make(chan struct{}, 1)
+	go acc.mergeEntropyTimeStamps(source, ch)
+	return ch
+}
+
+// mergeEntropyData is the goroutine body backing NewEntropyDataSink.
+func (acc *Accumulator) mergeEntropyData(source uint8, ch <-chan []byte) {
+	var poolCount uint32
+	for data := range ch {
+		acc.recordSample(source, data)
+		acc.mixIntoPool(source, poolCount, data)
+		poolCount++
+	}
+}
+
+// mergeEntropyTimeStamps is the goroutine body backing
+// NewEntropyTimeStampSink.
+func (acc *Accumulator) mergeEntropyTimeStamps(source uint8, ch <-chan struct{}) {
+	var poolCount uint32
+	for range ch {
+		data := int64ToBytes(time.Now().UnixNano())
+		acc.recordSample(source, data)
+		acc.mixIntoPool(source, poolCount, data)
+		poolCount++
+	}
+}
+
+// mixIntoPool writes one entropy event into pool number poolCount mod
+// numPools, using the (source number, event length, event data)
+// encoding of Ferguson & Schneier's Fortuna §9.5.1.  Entries which
+// land in pool 0 additionally count towards poolZeroSize, which
+// tryReseeding uses to decide when enough entropy has accumulated to
+// justify a reseed.
+func (acc *Accumulator) mixIntoPool(source uint8, poolCount uint32, data []byte) {
+	acc.poolMutex.Lock()
+	defer acc.poolMutex.Unlock()
+
+	pool := acc.pool[poolCount%numPools]
+	pool.Write([]byte{source, byte(len(data))})
+	pool.Write(data)
+
+	if poolCount%numPools == 0 {
+		acc.poolZeroSize += 2 + len(data)
+	}
+}
@@ -0,0 +1,81 @@
+// entropy_test.go - unit tests for entropy.go
+// Copyright (C) 2013  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seehuhn/sha256d"
+)
+
+func newTestAccumulatorWithPools() *Accumulator {
+	acc := newTestAccumulator()
+	for i := range acc.pool {
+		acc.pool[i] = sha256d.New()
+	}
+	return acc
+}
+
+func TestNewEntropyDataSinkUpdatesStatsAndPools(t *testing.T) {
+	acc := newTestAccumulatorWithPools()
+
+	sink := acc.NewEntropyDataSink()
+	sink <- []byte("some entropy")
+	close(sink)
+
+	deadline := time.After(time.Second)
+	for {
+		stats := acc.SourceStats()
+		if len(stats) == 1 && stats[0].BytesSubmitted == 12 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("SourceStats did not reflect the submitted sample in time: %+v", stats)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	acc.poolMutex.Lock()
+	zeroSize := acc.poolZeroSize
+	acc.poolMutex.Unlock()
+	if zeroSize == 0 {
+		t.Error("expected the submitted entropy to be mixed into pool 0")
+	}
+}
+
+func TestNewEntropyTimeStampSinkUpdatesStats(t *testing.T) {
+	acc := newTestAccumulatorWithPools()
+
+	sink := acc.NewEntropyTimeStampSink()
+	sink <- struct{}{}
+	close(sink)
+
+	deadline := time.After(time.Second)
+	for {
+		stats := acc.SourceStats()
+		if len(stats) == 1 && stats[0].BytesSubmitted > 0 {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("SourceStats did not reflect the time-stamp sample in time: %+v", stats)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// hash_drbg.go - a NIST SP 800-90A Hash_DRBG generator backend
+// Copyright (C) 2016  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"sync"
+)
+
+// hashDRBGSeedLen is the seedlen, in bytes, used by Hash_DRBG when
+// instantiated with SHA-256, as given in SP 800-90A table 2 (440
+// bits).
+const hashDRBGSeedLen = 55
+
+// hashDRBGMaxReseedCounter is the largest number of Generate calls
+// Hash_DRBG will serve between reseeds; once reseed_counter exceeds
+// it (the "reseed_counter > reseed_interval" check of SP 800-90A
+// §9.3), PseudoRandomData performs a forced reseed before generating.
+const hashDRBGMaxReseedCounter = 1 << 48
+
+// HashDRBG implements the Hash_DRBG construction from NIST SP
+// 800-90A §10.1.1, using SHA-256 as its hash function.  It satisfies
+// the same generatorBackend interface as Generator and
+// ChaCha20Generator and can therefore be used as an Accumulator's
+// generator via NewHashDRBGAccumulator, for applications which must
+// use a FIPS-style DRBG construction.
+//
+// A newly allocated HashDRBG is uninstantiated; its first Reseed
+// call (or, if output is requested first, its first PseudoRandomData
+// call, instantiating from an empty seed) performs the SP 800-90A
+// Instantiate step (using the seed as entropy_input, with empty
+// nonce and personalization_string, since an Accumulator's seed
+// already combines several independent entropy pools together with
+// the current time).  Subsequent Reseed calls perform the ordinary
+// SP 800-90A Reseed step.
+//
+// It is safe to access a HashDRBG concurrently from different
+// goroutines.
+type HashDRBG struct {
+	mutex sync.Mutex
+
+	instantiated  bool
+	v, c          [hashDRBGSeedLen]byte
+	reseedCounter uint64
+}
+
+// NewHashDRBG allocates a new, uninstantiated Hash_DRBG generator.
+// It can be used right away (as an empty-seed generator), but
+// Reseed should be called with real entropy (directly, or indirectly
+// via an Accumulator) as soon as it is available.
+func NewHashDRBG() *HashDRBG {
+	return &HashDRBG{}
+}
+
+// hashDF is the Hash_df counter-mode expansion from SP 800-90A
+// §10.3.1, using SHA-256 as the hash function.
+func hashDF(input []byte, numBytes int) []byte {
+	numBits := uint32(numBytes) * 8
+	out := make([]byte, 0, numBytes)
+	for counter := byte(1); len(out) < numBytes; counter++ {
+		h := sha256.New()
+		h.Write([]byte{counter})
+		var lenBuf [4]byte
+		lenBuf[0] = byte(numBits >> 24)
+		lenBuf[1] = byte(numBits >> 16)
+		lenBuf[2] = byte(numBits >> 8)
+		lenBuf[3] = byte(numBits)
+		h.Write(lenBuf[:])
+		h.Write(input)
+		out = h.Sum(out)
+	}
+	return out[:numBytes]
+}
+
+// seedLenAdd computes (a + b) mod 2^(8*hashDRBGSeedLen), where a and
+// b are hashDRBGSeedLen-byte big-endian values, and c is an
+// additional small non-negative addend (used for reseed_counter).
+func seedLenAdd(a, b [hashDRBGSeedLen]byte, c uint64) [hashDRBGSeedLen]byte {
+	x := new(big.Int).SetBytes(a[:])
+	y := new(big.Int).SetBytes(b[:])
+	x.Add(x, y)
+	x.Add(x, new(big.Int).SetUint64(c))
+
+	mod := new(big.Int).Lsh(big.NewInt(1), 8*hashDRBGSeedLen)
+	x.Mod(x, mod)
+
+	var out [hashDRBGSeedLen]byte
+	x.FillBytes(out[:])
+	return out
+}
+
+// instantiateLocked performs the SP 800-90A Instantiate_function
+// using entropy as the full seed_material (nonce and
+// personalization_string are empty).  g.mutex must be held.
+func (g *HashDRBG) instantiateLocked(entropy []byte) {
+	v := hashDF(entropy, hashDRBGSeedLen)
+	copy(g.v[:], v)
+
+	cInput := make([]byte, 0, 1+hashDRBGSeedLen)
+	cInput = append(cInput, 0x00)
+	cInput = append(cInput, g.v[:]...)
+	c := hashDF(cInput, hashDRBGSeedLen)
+	copy(g.c[:], c)
+
+	g.reseedCounter = 1
+	g.instantiated = true
+}
+
+// reseedLocked performs the SP 800-90A Reseed_function using entropy
+// as additional_input-free entropy_input.  g.mutex must be held.
+func (g *HashDRBG) reseedLocked(entropy []byte) {
+	seedMaterial := make([]byte, 0, 1+hashDRBGSeedLen+len(entropy))
+	seedMaterial = append(seedMaterial, 0x01)
+	seedMaterial = append(seedMaterial, g.v[:]...)
+	seedMaterial = append(seedMaterial, entropy...)
+
+	v := hashDF(seedMaterial, hashDRBGSeedLen)
+	copy(g.v[:], v)
+
+	cInput := make([]byte, 0, 1+hashDRBGSeedLen)
+	cInput = append(cInput, 0x00)
+	cInput = append(cInput, g.v[:]...)
+	c := hashDF(cInput, hashDRBGSeedLen)
+	copy(g.c[:], c)
+
+	g.reseedCounter = 1
+}
+
+// Reseed mixes seed into the Hash_DRBG state.  The first call
+// instantiates the generator (see the HashDRBG documentation);
+// subsequent calls perform the standard SP 800-90A reseed step.
+func (g *HashDRBG) Reseed(seed []byte) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.instantiated {
+		g.instantiateLocked(seed)
+		return
+	}
+	g.reseedLocked(seed)
+}
+
+// hashgen implements the Hashgen subroutine of SP 800-90A §10.1.1.2,
+// producing requestedBytes of output from data (a copy of V, which
+// is left unmodified by this function).
+func hashgen(data [hashDRBGSeedLen]byte, requestedBytes int) []byte {
+	out := make([]byte, 0, requestedBytes+sha256.Size)
+	for len(out) < requestedBytes {
+		h := sha256.Sum256(data[:])
+		out = append(out, h[:]...)
+
+		one := [hashDRBGSeedLen]byte{}
+		one[hashDRBGSeedLen-1] = 1
+		data = seedLenAdd(data, one, 0)
+	}
+	return out[:requestedBytes]
+}
+
+// PseudoRandomData returns n pseudo-random bytes, following the
+// Hash_DRBG Generate_function of SP 800-90A §10.1.1.4.  Like
+// Generator and ChaCha20Generator, a HashDRBG which has not been
+// seeded yet behaves as if it had been instantiated from an empty
+// seed, rather than panicking, so that it is a true drop-in
+// generatorBackend: callers such as Accumulator.RandomData may run
+// before the pool has accumulated enough entropy for its first
+// reseed.
+//
+// SP 800-90A §9.3 requires a DRBG to refuse to generate once
+// reseed_counter exceeds its reseed_interval, forcing the caller to
+// reseed first. Since generatorBackend has no way to report that back
+// to Accumulator.RandomData (which holds genMutex with no path to
+// recover from a panic), PseudoRandomData instead performs the
+// forced reseed itself, pulling fresh entropy straight from the OS
+// CSPRNG, and continues to serve the request.
+func (g *HashDRBG) PseudoRandomData(n uint) []byte {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.instantiated {
+		g.instantiateLocked(nil)
+	}
+	if g.reseedCounter > hashDRBGMaxReseedCounter {
+		entropy := make([]byte, hashDRBGSeedLen)
+		if _, err := rand.Read(entropy); err != nil {
+			panic("fortuna: HashDRBG forced reseed failed to read OS entropy: " + err.Error())
+		}
+		g.reseedLocked(entropy)
+	}
+
+	out := hashgen(g.v, int(n))
+
+	hInput := make([]byte, 0, 1+hashDRBGSeedLen)
+	hInput = append(hInput, 0x03)
+	hInput = append(hInput, g.v[:]...)
+	hSum := sha256.Sum256(hInput)
+	var h [hashDRBGSeedLen]byte
+	copy(h[hashDRBGSeedLen-sha256.Size:], hSum[:])
+
+	g.v = seedLenAdd(seedLenAdd(g.v, h, 0), g.c, g.reseedCounter)
+	g.reseedCounter++
+
+	return out
+}
+
+// reset erases the Hash_DRBG state so that previously generated
+// output cannot be recovered from it and the generator can no longer
+// be used.
+func (g *HashDRBG) reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	wipe(g.v[:])
+	wipe(g.c[:])
+	g.reseedCounter = 0
+	g.instantiated = false
+}
+
+// Seed uses the given int64 value as entropy input for a Hash_DRBG
+// reseed.  This mirrors Generator.Seed and is mainly useful for
+// writing reproducible tests; production code should reseed using
+// real entropy from an Accumulator instead.
+func (g *HashDRBG) Seed(seed int64) {
+	g.Reseed(int64ToBytes(seed))
+}
+
+// Int63 returns a positive random integer, uniformly distributed on
+// the range 0, 1, ..., 2^63-1.  This function is part of the
+// rand.Source interface.
+func (g *HashDRBG) Int63() int64 {
+	buf := g.PseudoRandomData(8)
+	buf[0] &= 0x7f
+	return bytesToInt64(buf)
+}
+
+// Uint64 returns a positive random integer, uniformly distributed on
+// the range 0, 1, ..., 2^64-1.  This function is part of the
+// rand.Source64 interface.
+func (g *HashDRBG) Uint64() uint64 {
+	return bytesToUint64(g.PseudoRandomData(8))
+}
+
+// Read allows randomness to be extracted from a HashDRBG using the
+// io.Reader interface.  Read fills p with random bytes, always reads
+// len(p) bytes and never returns an error.
+func (g *HashDRBG) Read(p []byte) (n int, err error) {
+	copy(p, g.PseudoRandomData(uint(len(p))))
+	return len(p), nil
+}
+
+// NewHashDRBGAccumulator allocates a new instance of the Fortuna
+// random number generator which uses a NIST SP 800-90A Hash_DRBG
+// (see HashDRBG) instead of the usual Fortuna block-cipher generator
+// to produce its output.  acc.tryReseeding's returned pool digest is
+// used, unchanged, as the entropy_input to the Hash_DRBG reseed
+// step.  See the documentation for NewRNG() for a description of the
+// seedFileName argument.
+func NewHashDRBGAccumulator(seedFileName string) (*Accumulator, error) {
+	return newAccumulator(NewHashDRBG(), seedFileName)
+}
@@ -0,0 +1,111 @@
+// hash_drbg_test.go - unit tests for hash_drbg.go
+// Copyright (C) 2016  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHashDRBGDeterministic(t *testing.T) {
+	g1 := NewHashDRBG()
+	g1.Reseed([]byte("hash drbg seed"))
+	out1 := g1.PseudoRandomData(80)
+
+	g2 := NewHashDRBG()
+	g2.Reseed([]byte("hash drbg seed"))
+	out2 := g2.PseudoRandomData(80)
+
+	if !bytes.Equal(out1, out2) {
+		t.Error("the same seed produced different output")
+	}
+}
+
+func TestHashDRBGUsableBeforeReseed(t *testing.T) {
+	g := NewHashDRBG()
+	out := g.PseudoRandomData(32)
+	if len(out) != 32 {
+		t.Fatalf("expected 32 bytes, got %d", len(out))
+	}
+	if isZero(out) {
+		t.Error("all-zero-seed output should not itself be all zero")
+	}
+}
+
+func TestHashDRBGReseedChangesOutput(t *testing.T) {
+	g := NewHashDRBG()
+	g.Reseed([]byte("first"))
+	out1 := g.PseudoRandomData(32)
+
+	g.Reseed([]byte("second"))
+	out2 := g.PseudoRandomData(32)
+
+	if bytes.Equal(out1, out2) {
+		t.Error("different seeds produced identical output")
+	}
+}
+
+func TestHashDRBGForcedReseedLimit(t *testing.T) {
+	g := NewHashDRBG()
+	g.Reseed([]byte("seed"))
+	g.reseedCounter = hashDRBGMaxReseedCounter + 1
+
+	out := g.PseudoRandomData(8)
+	if len(out) != 8 {
+		t.Fatalf("expected 8 bytes, got %d", len(out))
+	}
+	if g.reseedCounter != 2 {
+		t.Errorf("expected the forced reseed to reset reseedCounter to 1 before this call incremented it to 2, got %d", g.reseedCounter)
+	}
+}
+
+func TestHashDF(t *testing.T) {
+	out := hashDF([]byte("some entropy"), hashDRBGSeedLen)
+	if len(out) != hashDRBGSeedLen {
+		t.Fatalf("expected %d bytes, got %d", hashDRBGSeedLen, len(out))
+	}
+
+	out2 := hashDF([]byte("some other entropy"), hashDRBGSeedLen)
+	if bytes.Equal(out, out2) {
+		t.Error("Hash_df produced identical output for different input")
+	}
+}
+
+func TestHashgen(t *testing.T) {
+	var v [hashDRBGSeedLen]byte
+	for i := range v {
+		v[i] = byte(i)
+	}
+
+	out := hashgen(v, 100)
+	if len(out) != 100 {
+		t.Fatalf("expected 100 bytes, got %d", len(out))
+	}
+}
+
+func TestHashDRBGReset(t *testing.T) {
+	g := NewHashDRBG()
+	g.Reseed([]byte("seed"))
+	g.reset()
+
+	if g.instantiated {
+		t.Error("reset did not clear the instantiated flag")
+	}
+	if !isZero(g.v[:]) || !isZero(g.c[:]) {
+		t.Error("reset did not wipe V and C")
+	}
+}
@@ -0,0 +1,230 @@
+// health.go - per-source health monitoring for entropy sources
+// Copyright (C) 2018  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// SourceHealth describes the current trust level of an entropy
+// source, as tracked by the health-monitoring subsystem.
+type SourceHealth int
+
+const (
+	// SourceHealthy indicates that every sample seen so far from
+	// this source has passed the repetition-count and
+	// adaptive-proportion tests.
+	SourceHealthy SourceHealth = iota
+
+	// SourceTripped indicates that a sample from this source failed
+	// one of the health tests.  Once tripped, a source's further
+	// contributions are still mixed into the pools (since doing no
+	// harm requires no trust), but are no longer counted towards the
+	// accumulator's estimated entropy.
+	SourceTripped
+)
+
+const (
+	// repetitionCutoff is the number of consecutive identical
+	// samples from one source which trips the repetition-count
+	// detector.  This is a simplified stand-in for the
+	// min-entropy-derived cutoff of SP 800-90B §4.4.1.
+	repetitionCutoff = 34
+
+	// proportionWindow and proportionCutoff configure the
+	// adaptive-proportion detector of SP 800-90B §4.4.2: within the
+	// most recent proportionWindow samples, no single observed value
+	// may occur proportionCutoff times or more.
+	proportionWindow = 512
+	proportionCutoff = proportionWindow/2 + 16
+)
+
+// sourceHealth holds the repetition-count and adaptive-proportion
+// detector state, plus simple usage statistics, for one entropy
+// source.  Each detector follows data, a single byte representative
+// of a submitted sample (see foldSample); this is a considerable
+// simplification of SP 800-90B's full continuous health tests, which
+// are designed for a noise source's raw, un-conditioned output.
+type sourceHealth struct {
+	bytesSubmitted   uint64
+	lastSample       time.Time
+	lastEstimateBits float64
+	status           SourceHealth
+
+	haveLastSymbol bool
+	lastSymbol     byte
+	repeatCount    int
+
+	window     [proportionWindow]byte
+	windowLen  int
+	windowNext int
+	counts     [256]int
+}
+
+func newSourceHealth() *sourceHealth {
+	return &sourceHealth{}
+}
+
+// foldSample reduces an entropy sample to a single byte, by XORing
+// together all of its bytes, for use as the "symbol" tracked by the
+// repetition-count and adaptive-proportion detectors.
+func foldSample(data []byte) byte {
+	var b byte
+	for _, x := range data {
+		b ^= x
+	}
+	return b
+}
+
+// update folds in one newly submitted sample, running both health
+// tests and updating the source's estimated-entropy statistics.
+func (h *sourceHealth) update(data []byte) {
+	h.bytesSubmitted += uint64(len(data))
+	h.lastSample = time.Now()
+	symbol := foldSample(data)
+
+	if h.haveLastSymbol && symbol == h.lastSymbol {
+		h.repeatCount++
+	} else {
+		h.haveLastSymbol = true
+		h.lastSymbol = symbol
+		h.repeatCount = 1
+	}
+	if h.repeatCount >= repetitionCutoff {
+		h.status = SourceTripped
+	}
+
+	if h.windowLen == proportionWindow {
+		h.counts[h.window[h.windowNext]]--
+	} else {
+		h.windowLen++
+	}
+	h.window[h.windowNext] = symbol
+	h.windowNext = (h.windowNext + 1) % proportionWindow
+	h.counts[symbol]++
+	if h.windowLen == proportionWindow && h.counts[symbol] >= proportionCutoff {
+		h.status = SourceTripped
+	}
+
+	if h.status == SourceTripped {
+		h.lastEstimateBits = 0
+		return
+	}
+
+	// Conservative fixed estimate of one bit of entropy per byte of
+	// raw sample data, capped at 8 bits per sample.  This avoids
+	// overclaiming entropy for sources which submit long, mostly
+	// predictable samples.
+	bits := float64(len(data))
+	if bits > 8 {
+		bits = 8
+	}
+	h.lastEstimateBits = bits
+}
+
+// recordSample updates the health-monitoring statistics for the
+// given source index and, if the source is currently healthy, adds
+// its estimated entropy to the accumulator's running total used by
+// WaitForSeed.  It is called by mergeEntropyData and
+// mergeEntropyTimeStamps (see entropy.go) for every sample submitted
+// through a channel allocated by NewEntropyDataSink or
+// NewEntropyTimeStampSink — which includes StartOSEntropySources'
+// built-in sources, since those are themselves implemented on top of
+// the same two channel types.
+func (acc *Accumulator) recordSample(source uint8, data []byte) {
+	acc.healthMutex.Lock()
+	h := acc.health[source]
+	if h == nil {
+		h = newSourceHealth()
+		acc.health[source] = h
+	}
+	h.update(data)
+	bits := h.lastEstimateBits
+	acc.healthMutex.Unlock()
+
+	if bits <= 0 {
+		return
+	}
+	acc.seedMutex.Lock()
+	acc.seedBits += bits
+	acc.seedCond.Broadcast()
+	acc.seedMutex.Unlock()
+}
+
+// SourceStat is a snapshot of the health-monitoring state of a single
+// entropy source, as returned by Accumulator.SourceStats.
+type SourceStat struct {
+	Source         uint8
+	BytesSubmitted uint64
+	EstimatedBits  float64
+	LastSample     time.Time
+	Health         SourceHealth
+}
+
+// SourceStats returns a snapshot of the health-monitoring state of
+// every entropy source which has submitted at least one sample so
+// far, ordered by source index.
+func (acc *Accumulator) SourceStats() []SourceStat {
+	acc.healthMutex.Lock()
+	defer acc.healthMutex.Unlock()
+
+	stats := make([]SourceStat, 0, len(acc.health))
+	for source, h := range acc.health {
+		stats = append(stats, SourceStat{
+			Source:         source,
+			BytesSubmitted: h.bytesSubmitted,
+			EstimatedBits:  h.lastEstimateBits,
+			LastSample:     h.lastSample,
+			Health:         h.status,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Source < stats[j].Source })
+	return stats
+}
+
+// WaitForSeed blocks until the accumulator has absorbed at least
+// minBits of estimated entropy from its healthy sources, or until ctx
+// is done, whichever happens first.  This mirrors the guarantee
+// Linux's getrandom(2) gives to GRND_RANDOM callers, and is intended
+// for early-boot code paths in security-critical daemons which must
+// not start producing output from a cold pool.
+func (acc *Accumulator) WaitForSeed(ctx context.Context, minBits float64) error {
+	done := make(chan struct{})
+	go func() {
+		acc.seedMutex.Lock()
+		defer acc.seedMutex.Unlock()
+		for acc.seedBits < minBits && ctx.Err() == nil {
+			acc.seedCond.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return ctx.Err()
+	case <-ctx.Done():
+		// Wake the helper goroutine so that it observes ctx.Err()
+		// and exits instead of waiting forever.
+		acc.seedMutex.Lock()
+		acc.seedCond.Broadcast()
+		acc.seedMutex.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
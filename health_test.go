@@ -0,0 +1,112 @@
+// health_test.go - unit tests for health.go
+// Copyright (C) 2018  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestAccumulator() *Accumulator {
+	acc := &Accumulator{health: make(map[uint8]*sourceHealth)}
+	acc.seedCond = sync.NewCond(&acc.seedMutex)
+	return acc
+}
+
+func TestSourceHealthRepetitionDetector(t *testing.T) {
+	h := newSourceHealth()
+	sample := []byte{1, 2, 3}
+	for i := 0; i < repetitionCutoff; i++ {
+		h.update(sample)
+	}
+	if h.status != SourceTripped {
+		t.Error("expected the repetition-count detector to trip")
+	}
+}
+
+func TestSourceHealthProportionDetector(t *testing.T) {
+	h := newSourceHealth()
+	// Repeat symbol 7 for 3 out of every 5 samples: frequent enough
+	// to push its count within the window past proportionCutoff
+	// (272 of 512), but with runs short enough (at most 4 in a row,
+	// where byte(263) is also 7) that the repetition-count detector
+	// never trips first.
+	for i := 0; i < proportionWindow; i++ {
+		if i%5 < 3 {
+			h.update([]byte{7})
+		} else {
+			h.update([]byte{byte(i)})
+		}
+	}
+	if h.status != SourceTripped {
+		t.Error("expected the adaptive-proportion detector to trip")
+	}
+}
+
+func TestSourceHealthStaysHealthy(t *testing.T) {
+	h := newSourceHealth()
+	for i := 0; i < 50; i++ {
+		h.update([]byte{byte(i)})
+	}
+	if h.status != SourceHealthy {
+		t.Error("expected varied samples to leave the source healthy")
+	}
+}
+
+func TestRecordSampleAndSourceStats(t *testing.T) {
+	acc := newTestAccumulator()
+
+	acc.recordSample(3, []byte{1, 2, 3, 4})
+	stats := acc.SourceStats()
+	if len(stats) != 1 || stats[0].Source != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats[0].BytesSubmitted != 4 {
+		t.Errorf("expected 4 bytes submitted, got %d", stats[0].BytesSubmitted)
+	}
+	if stats[0].Health != SourceHealthy {
+		t.Errorf("expected a fresh source to be healthy, got %v", stats[0].Health)
+	}
+}
+
+func TestWaitForSeedUnblocksOnceEnoughEntropy(t *testing.T) {
+	acc := newTestAccumulator()
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			acc.recordSample(1, []byte{byte(i), byte(i * 7)})
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := acc.WaitForSeed(ctx, 10); err != nil {
+		t.Fatalf("WaitForSeed returned an error: %v", err)
+	}
+}
+
+func TestWaitForSeedRespectsContextCancellation(t *testing.T) {
+	acc := newTestAccumulator()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := acc.WaitForSeed(ctx, 1e9); err == nil {
+		t.Error("expected WaitForSeed to report the context error")
+	}
+}
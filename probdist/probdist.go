@@ -0,0 +1,179 @@
+// probdist.go - weighted sampling on top of a Fortuna accumulator
+// Copyright (C) 2017  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package probdist provides cryptographically-strong sampling from
+// arbitrary discrete weighted distributions, built on top of a
+// fortuna.Accumulator.  Sampling uses Vose's alias method, so after
+// an O(n) setup each draw takes constant time regardless of the
+// number of outcomes.
+//
+// This is useful, for example, for building pluggable-transport-style
+// packet length or timing obfuscation on top of Fortuna, without
+// applications needing a separate CSPRNG for that purpose.
+package probdist
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/seehuhn/fortuna"
+	"golang.org/x/crypto/hkdf"
+)
+
+// hkdfInfo distinguishes probdist's use of HKDF from other uses of
+// the same seed elsewhere in an application.
+var hkdfInfo = []byte("github.com/seehuhn/fortuna/probdist")
+
+// WeightedDist draws samples from a fixed discrete distribution over
+// the outcomes 0, 1, ..., n-1, where outcome i is drawn with
+// probability proportional to the weight given for i in the call to
+// NewWeightedDist.
+//
+// It is safe to call Sample concurrently from different goroutines.
+type WeightedDist struct {
+	prob  []float64
+	alias []int
+
+	mutex     sync.Mutex
+	randBytes func(n int) []byte
+}
+
+// NewWeightedDist builds a WeightedDist for the distribution given by
+// weights, which must be non-negative and not all zero.
+//
+// If acc is non-nil, each sample's randomness is drawn directly from
+// acc, giving cryptographically-strong, non-reproducible output; seed
+// is ignored in this case. If acc is nil, samples are instead
+// deterministically derived from an HKDF-SHA256 expansion of seed, so
+// that the same seed always reproduces the same sequence of samples.
+func NewWeightedDist(acc *fortuna.Accumulator, weights []float64, seed []byte) *WeightedDist {
+	prob, alias := buildAliasTables(weights)
+	wd := &WeightedDist{prob: prob, alias: alias}
+
+	if acc != nil {
+		wd.randBytes = func(n int) []byte {
+			return acc.RandomData(uint(n))
+		}
+		return wd
+	}
+
+	// hkdf.Expand (and so hkdf.New's returned io.Reader) can only ever
+	// produce 255*sha256.Size = 8160 bytes before returning an error,
+	// which is far too little for a sampler meant to run for the
+	// lifetime of a long-lived connection. Work around this by
+	// deriving a fresh sub-key, via HKDF-Expand with a batch counter
+	// folded into the info/context, every hkdfBatchSamples draws,
+	// instead of treating a single Expand reader as an infinite
+	// stream.
+	var batch uint64
+	var batchReader io.Reader
+	var batchLeft int
+	wd.randBytes = func(n int) []byte {
+		if batchLeft == 0 {
+			info := make([]byte, len(hkdfInfo)+8)
+			copy(info, hkdfInfo)
+			binary.BigEndian.PutUint64(info[len(hkdfInfo):], batch)
+			batchReader = hkdf.New(sha256.New, seed, nil, info)
+			batch++
+			batchLeft = hkdfBatchSamples
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(batchReader, buf); err != nil {
+			// Cannot happen: each batch only ever reads
+			// hkdfBatchSamples*8 bytes, well under the 8160-byte
+			// limit of a single HKDF-SHA256 Expand.
+			panic(err)
+		}
+		batchLeft--
+		return buf
+	}
+	return wd
+}
+
+// hkdfBatchSamples is the number of 8-byte draws served from a single
+// HKDF-Expand reader before probdist derives a fresh one. At 8 bytes
+// per draw, hkdfBatchSamples*8 must stay comfortably under HKDF's
+// 255*sha256.Size = 8160 byte limit per Expand call.
+const hkdfBatchSamples = 256
+
+// buildAliasTables builds the prob and alias tables used by Vose's
+// alias method (Vose, 1991) for the given, non-negative weights.
+func buildAliasTables(weights []float64) (prob []float64, alias []int) {
+	n := len(weights)
+	prob = make([]float64, n)
+	alias = make([]int, n)
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	for len(small) > 0 && len(large) > 0 {
+		s := small[len(small)-1]
+		small = small[:len(small)-1]
+		l := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		prob[s] = scaled[s]
+		alias[s] = l
+
+		scaled[l] = scaled[l] + scaled[s] - 1
+		if scaled[l] < 1 {
+			small = append(small, l)
+		} else {
+			large = append(large, l)
+		}
+	}
+
+	// Leftover entries only fail to reach exactly 1 because of
+	// floating-point rounding; treat them as certain.
+	for _, i := range large {
+		prob[i] = 1
+	}
+	for _, i := range small {
+		prob[i] = 1
+	}
+
+	return prob, alias
+}
+
+// Sample draws one outcome from the distribution, in 0, 1, ..., n-1.
+func (wd *WeightedDist) Sample() int {
+	wd.mutex.Lock()
+	buf := wd.randBytes(8)
+	wd.mutex.Unlock()
+
+	i := int(binary.LittleEndian.Uint32(buf[:4]) % uint32(len(wd.prob)))
+	u := float64(binary.LittleEndian.Uint32(buf[4:])) / float64(uint64(1)<<32)
+
+	if u < wd.prob[i] {
+		return i
+	}
+	return wd.alias[i]
+}
@@ -0,0 +1,118 @@
+// probdist_test.go - unit tests for probdist.go
+// Copyright (C) 2017  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package probdist
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBuildAliasTablesMarginalProbabilities(t *testing.T) {
+	// A table entry's own prob[i] is not, in general, the marginal
+	// probability of outcome i: Vose's alias method can repair entry
+	// i as the "small" side of a later iteration, leaving prob[i]
+	// below 1 while outcome i also receives probability through other
+	// entries' aliases (e.g. for weights {1, 2, 3, 4}, prob[3] ends up
+	// at 0.8 and alias[0] = alias[1] = 3, while P(outcome=3) is still
+	// correctly 4/10). So check the marginal
+	// probabilities the tables actually produce, by simulating draws
+	// through the same logic Sample() uses, instead of asserting
+	// anything about individual prob[] entries.
+	weights := []float64{1, 2, 3, 4}
+	prob, alias := buildAliasTables(weights)
+
+	if len(prob) != len(weights) || len(alias) != len(weights) {
+		t.Fatalf("unexpected table sizes: %d, %d", len(prob), len(alias))
+	}
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	const numDraws = 200000
+	counts := make([]int, len(weights))
+	for i := 0; i < numDraws; i++ {
+		// Deterministic stand-ins for Sample()'s two random draws,
+		// cycling through the unit interval and the outcome range so
+		// that every (column, coin) combination is exercised evenly.
+		k := i % len(weights)
+		coin := float64(i/len(weights)%1000) / 1000
+		outcome := k
+		if coin >= prob[k] {
+			outcome = alias[k]
+		}
+		counts[outcome]++
+	}
+
+	for i, w := range weights {
+		expected := w / total
+		observed := float64(counts[i]) / float64(numDraws)
+		if diff := math.Abs(observed - expected); diff > 0.01 {
+			t.Errorf("outcome %d: expected marginal probability %.4f, got %.4f", i, expected, observed)
+		}
+	}
+}
+
+func TestSampleDistribution(t *testing.T) {
+	weights := []float64{1, 0, 3}
+	wd := NewWeightedDist(nil, weights, []byte("probdist test seed"))
+
+	const n = 20000
+	counts := make([]int, len(weights))
+	for i := 0; i < n; i++ {
+		s := wd.Sample()
+		if s < 0 || s >= len(weights) {
+			t.Fatalf("sample out of range: %d", s)
+		}
+		counts[s]++
+	}
+
+	if counts[1] != 0 {
+		t.Errorf("outcome with zero weight was sampled %d times", counts[1])
+	}
+
+	ratio := float64(counts[2]) / float64(counts[0])
+	if math.Abs(ratio-3) > 0.5 {
+		t.Errorf("sampled ratio %v is far from the expected 3", ratio)
+	}
+}
+
+func TestSampleDeterministic(t *testing.T) {
+	weights := []float64{1, 1, 1}
+	seed := []byte("deterministic seed")
+
+	wd1 := NewWeightedDist(nil, weights, seed)
+	wd2 := NewWeightedDist(nil, weights, seed)
+
+	for i := 0; i < 100; i++ {
+		if wd1.Sample() != wd2.Sample() {
+			t.Fatalf("the same seed produced different sequences at draw %d", i)
+		}
+	}
+}
+
+func TestSampleCrossesHKDFBatchBoundary(t *testing.T) {
+	weights := []float64{1, 1}
+	wd := NewWeightedDist(nil, weights, []byte("batch boundary seed"))
+
+	// This must draw past hkdfBatchSamples and force at least one
+	// fresh HKDF sub-key derivation, without panicking.
+	for i := 0; i < hkdfBatchSamples+10; i++ {
+		wd.Sample()
+	}
+}
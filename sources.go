@@ -0,0 +1,104 @@
+// sources.go - platform-independent plumbing for OS entropy sources
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"crypto/aes"
+	"time"
+)
+
+// osEntropySampleInterval is the default rate at which the built-in
+// OS entropy sources are sampled.  It is deliberately coarse: the
+// sources are meant to complement, not replace, entropy submitted by
+// the calling application.
+const osEntropySampleInterval = 500 * time.Millisecond
+
+// StartOSEntropySources spawns one long-running goroutine per
+// platform-appropriate entropy source and starts feeding their
+// output into the accumulator's pools.  Which sources are used
+// depends on the build target; see sources_linux.go,
+// sources_windows.go, sources_plan9.go and sources_other.go.
+//
+// The goroutines run until acc.Close is called.  Calling
+// StartOSEntropySources removes the need for callers to manually
+// wire up NewEntropyDataSink/NewEntropyTimeStampSink in order to get
+// a reasonable default source of real-world randomness.
+func (acc *Accumulator) StartOSEntropySources() {
+	startOSEntropySources(acc)
+}
+
+// NewRNGWithOSEntropy is like NewRNG, but additionally calls
+// StartOSEntropySources on the returned Accumulator before returning
+// it.
+func NewRNGWithOSEntropy(seedFileName string) (*Accumulator, error) {
+	return NewAccumulatorWithOSEntropy(aes.NewCipher, seedFileName)
+}
+
+// NewAccumulatorWithOSEntropy is like NewAccumulator, but
+// additionally calls StartOSEntropySources on the returned
+// Accumulator before returning it.
+func NewAccumulatorWithOSEntropy(newCipher NewCipher, seedFileName string) (*Accumulator, error) {
+	acc, err := NewAccumulator(newCipher, seedFileName)
+	if err != nil {
+		return nil, err
+	}
+	acc.StartOSEntropySources()
+	return acc, nil
+}
+
+// allocateSourceID reserves and returns the next free per-source
+// index, using the same nextSource counter that NewEntropyDataSink
+// and NewEntropyTimeStampSink allocate from.
+func (acc *Accumulator) allocateSourceID() uint8 {
+	acc.sourceMutex.Lock()
+	defer acc.sourceMutex.Unlock()
+	id := acc.nextSource
+	acc.nextSource++
+	return id
+}
+
+// foldBytes XORs data down to n bytes, cycling through the output
+// buffer.  It is used by sources_linux.go's runProcFileSource to
+// reduce an arbitrarily large sample to a small, fixed-size one.
+func foldBytes(data []byte, n int) []byte {
+	out := make([]byte, n)
+	for i, b := range data {
+		out[i%n] ^= b
+	}
+	return out
+}
+
+// runEntropySource is a small helper shared by the platform-specific
+// source files.  It calls sample every interval until acc.Close has
+// closed acc.stopSources, and registers the goroutine with
+// acc.sources so that Close can wait for it to finish.
+func (acc *Accumulator) runEntropySource(interval time.Duration, sample func()) {
+	acc.sources.Add(1)
+	go func() {
+		defer acc.sources.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-acc.stopSources:
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+}
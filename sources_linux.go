@@ -0,0 +1,73 @@
+//go:build linux
+
+// sources_linux.go - Linux entropy sources for StartOSEntropySources
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// startOSEntropySources wires up the Linux entropy sources: periodic
+// reads from /dev/urandom, getrandom(2) where the kernel supports it,
+// and XOR-folded snapshots of a few /proc files whose contents depend
+// on scheduler and interrupt timing.
+func startOSEntropySources(acc *Accumulator) {
+	urandomSink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval, func() {
+		f, err := os.Open("/dev/urandom")
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		buf := make([]byte, 32)
+		if _, err := f.Read(buf); err == nil {
+			urandomSink <- buf
+		}
+	})
+
+	getrandomSink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval, func() {
+		buf := make([]byte, 32)
+		n, err := unix.Getrandom(buf, 0)
+		if err == nil && n > 0 {
+			getrandomSink <- buf[:n]
+		}
+	})
+
+	acc.runProcFileSource("/proc/timer_list")
+	acc.runProcFileSource("/proc/stat")
+	acc.runProcFileSource("/proc/interrupts")
+}
+
+// runProcFileSource feeds periodic snapshots of the /proc file at
+// path into the accumulator, each XOR-folded down to 8 bytes by
+// foldBytes.  Individual bytes of
+// these files carry very little entropy, but their exact contents
+// are hard for an attacker to predict on a running system.
+func (acc *Accumulator) runProcFileSource(path string) {
+	sink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval, func() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		sink <- foldBytes(data, 8)
+	})
+}
@@ -0,0 +1,34 @@
+//go:build !linux && !windows && !plan9
+
+// sources_other.go - fallback entropy source for StartOSEntropySources
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import "crypto/rand"
+
+// startOSEntropySources is the fallback used on platforms without a
+// more specific implementation: it simply feeds periodic reads of
+// crypto/rand.Reader into the accumulator.
+func startOSEntropySources(acc *Accumulator) {
+	sink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval, func() {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err == nil {
+			sink <- buf
+		}
+	})
+}
@@ -0,0 +1,41 @@
+//go:build plan9
+
+// sources_plan9.go - Plan 9 entropy source for StartOSEntropySources
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import "os"
+
+// startOSEntropySources wires up the Plan 9 entropy source: periodic
+// reads from /dev/random, which on Plan 9 is fed by an X9.31
+// generator seeded from hardware timing jitter rather than being a
+// raw hardware noise source itself.
+func startOSEntropySources(acc *Accumulator) {
+	sink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval, func() {
+		f, err := os.Open("/dev/random")
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		buf := make([]byte, 32)
+		n, err := f.Read(buf)
+		if err == nil && n > 0 {
+			sink <- buf[:n]
+		}
+	})
+}
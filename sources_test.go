@@ -0,0 +1,72 @@
+// sources_test.go - unit tests for sources.go
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAllocateSourceIDIncrements(t *testing.T) {
+	acc := newTestAccumulator()
+
+	for want := uint8(0); want < 5; want++ {
+		if got := acc.allocateSourceID(); got != want {
+			t.Fatalf("expected source id %d, got %d", want, got)
+		}
+	}
+}
+
+func TestFoldBytes(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	out := foldBytes(data, 4)
+
+	want := []byte{1 ^ 5 ^ 9, 2 ^ 6 ^ 10, 3 ^ 7, 4 ^ 8}
+	if len(out) != len(want) {
+		t.Fatalf("expected %d bytes, got %d", len(want), len(out))
+	}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Errorf("byte %d: expected %#x, got %#x", i, want[i], out[i])
+		}
+	}
+}
+
+func TestRunEntropySourceRespectsStopSources(t *testing.T) {
+	acc := newTestAccumulator()
+	acc.stopSources = make(chan bool)
+
+	var mu sync.Mutex
+	calls := 0
+	acc.runEntropySource(2*time.Millisecond, func() {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	close(acc.stopSources)
+	acc.sources.Wait()
+
+	mu.Lock()
+	n := calls
+	mu.Unlock()
+	if n == 0 {
+		t.Error("expected sample to run at least once before stopSources was closed")
+	}
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+// sources_windows.go - Windows entropy sources for StartOSEntropySources
+// Copyright (C) 2015  Jochen Voss <voss@seehuhn.de>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fortuna
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modbcrypt           = windows.NewLazySystemDLL("bcrypt.dll")
+	procBCryptGenRandom = modbcrypt.NewProc("BCryptGenRandom")
+)
+
+// bCryptUseSystemPreferredRNG tells BCryptGenRandom to use Windows'
+// system-preferred RNG algorithm instead of a caller-supplied
+// algorithm handle, matching how crypto/rand uses the function.
+const bCryptUseSystemPreferredRNG = 0x00000002
+
+// bCryptGenRandom fills buf using BCryptGenRandom from bcrypt.dll.
+func bCryptGenRandom(buf []byte) error {
+	if len(buf) == 0 {
+		return nil
+	}
+	if err := procBCryptGenRandom.Find(); err != nil {
+		return err
+	}
+	r, _, _ := procBCryptGenRandom.Call(
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+		uintptr(bCryptUseSystemPreferredRNG),
+	)
+	if r != 0 { // non-zero is an NTSTATUS error code
+		return windows.NTStatus(r).Errno()
+	}
+	return nil
+}
+
+// startOSEntropySources wires up the Windows entropy sources:
+// BCryptGenRandom, falling back to RtlGenRandom on systems where CNG
+// is unavailable, for cryptographic-quality bytes; and
+// QueryPerformanceCounter jitter sampled at a higher rate to capture
+// scheduler timing noise.
+func startOSEntropySources(acc *Accumulator) {
+	cryptoSink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval, func() {
+		buf := make([]byte, 32)
+		if err := bCryptGenRandom(buf); err == nil {
+			cryptoSink <- buf
+			return
+		}
+		if err := windows.RtlGenRandom(buf); err == nil {
+			cryptoSink <- buf
+		}
+	})
+
+	jitterSink := acc.NewEntropyDataSink()
+	acc.runEntropySource(osEntropySampleInterval/4, func() {
+		var counter int64
+		if err := windows.QueryPerformanceCounter(&counter); err == nil {
+			jitterSink <- int64ToBytes(counter)
+		}
+	})
+}